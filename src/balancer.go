@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// upstream is one backend URL behind a balancer, guarded by its own circuit
+// breaker so a single failing backend doesn't drag down its healthy peers.
+type upstream struct {
+	url     string
+	breaker *circuitBreaker
+}
+
+// balancer round-robins across a fixed set of upstreams, skipping any whose
+// circuit breaker is currently open.
+type balancer struct {
+	mu        sync.Mutex
+	upstreams []*upstream
+	next      int
+}
+
+func newBalancer(urls []string, breakerMaxFailures int, breakerCooldown time.Duration) *balancer {
+	upstreams := make([]*upstream, len(urls))
+	for i, url := range urls {
+		upstreams[i] = &upstream{url: url, breaker: newCircuitBreaker(breakerMaxFailures, breakerCooldown)}
+	}
+	return &balancer{upstreams: upstreams}
+}
+
+// pick returns the next healthy upstream in round-robin order.
+func (b *balancer) pick() (*upstream, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i := 0; i < len(b.upstreams); i++ {
+		idx := (b.next + i) % len(b.upstreams)
+		if b.upstreams[idx].breaker.allow() {
+			b.next = (idx + 1) % len(b.upstreams)
+			return b.upstreams[idx], nil
+		}
+	}
+	return nil, fmt.Errorf("no healthy upstream available")
+}
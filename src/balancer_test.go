@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBalancerPickRoundRobin(t *testing.T) {
+	b := newBalancer([]string{"a", "b", "c"}, 1, time.Minute)
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		up, err := b.pick()
+		if err != nil {
+			t.Fatalf("pick: %v", err)
+		}
+		got = append(got, up.url)
+	}
+
+	want := []string{"a", "b", "c", "a"}
+	for i, url := range want {
+		if got[i] != url {
+			t.Fatalf("pick #%d = %q, want %q", i, got[i], url)
+		}
+	}
+}
+
+func TestBalancerSkipsUpstreamWithOpenBreaker(t *testing.T) {
+	b := newBalancer([]string{"a", "b"}, 1, time.Minute)
+
+	up, err := b.pick()
+	if err != nil {
+		t.Fatalf("pick: %v", err)
+	}
+	if up.url != "a" {
+		t.Fatalf("first pick = %q, want %q", up.url, "a")
+	}
+	up.breaker.recordFailure()
+
+	for i := 0; i < 3; i++ {
+		next, err := b.pick()
+		if err != nil {
+			t.Fatalf("pick: %v", err)
+		}
+		if next.url != "b" {
+			t.Fatalf("pick while %q is open = %q, want %q", "a", next.url, "b")
+		}
+	}
+}
+
+func TestBalancerAllUnhealthyReturnsError(t *testing.T) {
+	b := newBalancer([]string{"a", "b"}, 1, time.Minute)
+	for _, up := range b.upstreams {
+		up.breaker.recordFailure()
+	}
+
+	if _, err := b.pick(); err == nil {
+		t.Fatal("expected an error when every upstream's breaker is open")
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAfterMaxFailures(t *testing.T) {
+	b := newCircuitBreaker(3, 50*time.Millisecond)
+
+	b.recordFailure()
+	b.recordFailure()
+	if !b.allow() {
+		t.Fatal("breaker should still be closed before reaching maxFailures")
+	}
+
+	b.recordFailure()
+	if b.allow() {
+		t.Fatal("breaker should be open immediately after the maxFailures-th failure")
+	}
+}
+
+func TestCircuitBreakerHalfOpensAfterCooldown(t *testing.T) {
+	b := newCircuitBreaker(1, 20*time.Millisecond)
+
+	b.recordFailure()
+	if b.allow() {
+		t.Fatal("breaker should be open right after tripping")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if !b.allow() {
+		t.Fatal("breaker should allow a trial request once the cooldown has elapsed")
+	}
+}
+
+func TestCircuitBreakerRecordSuccessResets(t *testing.T) {
+	b := newCircuitBreaker(2, time.Minute)
+
+	b.recordFailure()
+	b.recordSuccess()
+	b.recordFailure()
+	if !b.allow() {
+		t.Fatal("a success should reset the failure count, so one more failure shouldn't trip the breaker")
+	}
+}
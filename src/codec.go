@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Response is what onMessage got back from the upstream HTTP call, ready to
+// be framed back to the TCP peer.
+type Response struct {
+	Status  int
+	Body    []byte
+	Headers http.Header
+}
+
+// Codec reads one framed message from r, returning the raw payload and any
+// codec-specific metadata to merge into the outgoing JSON message. It also
+// knows how to frame the upstream response back to the same peer, using
+// whatever metadata ReadFrame produced for that request (e.g. a JSON-RPC id).
+//
+// r is a *bufio.Reader owned by the caller for the lifetime of the
+// connection, not a fresh wrapper per call: codecs must not discard it after
+// one frame, or bytes already buffered past the frame boundary are lost.
+type Codec interface {
+	ReadFrame(r *bufio.Reader) ([]byte, map[string]interface{}, error)
+	WriteResponse(w io.Writer, resp Response, meta map[string]interface{}) error
+}
+
+// NewlineCodec reads a single line terminated by '\n', the original framing
+// used by this tool.
+type NewlineCodec struct{}
+
+func (NewlineCodec) ReadFrame(r *bufio.Reader) ([]byte, map[string]interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, nil, err
+	}
+	return []byte(strings.TrimSpace(line)), nil, nil
+}
+
+func (NewlineCodec) WriteResponse(w io.Writer, resp Response, _ map[string]interface{}) error {
+	_, err := fmt.Fprintf(w, "%s\n", resp.Body)
+	return err
+}
+
+// LengthPrefixCodec reads a 4-byte big-endian length followed by that many
+// bytes of payload.
+type LengthPrefixCodec struct{}
+
+func (LengthPrefixCodec) ReadFrame(r *bufio.Reader) ([]byte, map[string]interface{}, error) {
+	var size uint32
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return nil, nil, err
+	}
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, nil, err
+	}
+	return buf, nil, nil
+}
+
+func (LengthPrefixCodec) WriteResponse(w io.Writer, resp Response, _ map[string]interface{}) error {
+	if err := binary.Write(w, binary.BigEndian, uint32(len(resp.Body))); err != nil {
+		return err
+	}
+	_, err := w.Write(resp.Body)
+	return err
+}
+
+// NetstringCodec reads a netstring-framed payload: "<length>:<payload>,".
+type NetstringCodec struct{}
+
+func (NetstringCodec) ReadFrame(r *bufio.Reader) ([]byte, map[string]interface{}, error) {
+	lengthPart, err := r.ReadString(':')
+	if err != nil {
+		return nil, nil, err
+	}
+	size, err := strconv.Atoi(lengthPart[:len(lengthPart)-1])
+	if err != nil {
+		return nil, nil, fmt.Errorf("netstring: invalid length %q: %w", lengthPart, err)
+	}
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, nil, err
+	}
+	comma, err := r.ReadByte()
+	if err != nil {
+		return nil, nil, err
+	}
+	if comma != ',' {
+		return nil, nil, fmt.Errorf("netstring: expected trailing ',', got %q", comma)
+	}
+	return buf, nil, nil
+}
+
+func (NetstringCodec) WriteResponse(w io.Writer, resp Response, _ map[string]interface{}) error {
+	_, err := fmt.Fprintf(w, "%d:%s,", len(resp.Body), resp.Body)
+	return err
+}
+
+// HTTPCodec reads a full HTTP request and forwards its body as the payload,
+// with method, path and headers carried as metadata.
+type HTTPCodec struct{}
+
+func (HTTPCodec) ReadFrame(r *bufio.Reader) ([]byte, map[string]interface{}, error) {
+	req, err := http.ReadRequest(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer req.Body.Close()
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	headers := map[string]string{}
+	for key := range req.Header {
+		headers[key] = req.Header.Get(key)
+	}
+	meta := map[string]interface{}{
+		"method":  req.Method,
+		"path":    req.URL.Path,
+		"headers": headers,
+	}
+	return body, meta, nil
+}
+
+func (HTTPCodec) WriteResponse(w io.Writer, resp Response, _ map[string]interface{}) error {
+	httpResp := &http.Response{
+		StatusCode:    resp.Status,
+		Status:        http.StatusText(resp.Status),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        resp.Headers,
+		Body:          io.NopCloser(strings.NewReader(string(resp.Body))),
+		ContentLength: int64(len(resp.Body)),
+	}
+	return httpResp.Write(w)
+}
+
+// JSONRPCCodec reads a single JSON-RPC 2.0 request object, carrying its id
+// and method as metadata alongside the raw params as the payload.
+type JSONRPCCodec struct{}
+
+// byteReader hands out one byte per Read, so a json.Decoder layered on top of
+// a shared *bufio.Reader stops at the end of the value it decodes instead of
+// buffering (and then dropping) the bytes that follow it.
+type byteReader struct{ r *bufio.Reader }
+
+func (b byteReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	c, err := b.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	p[0] = c
+	return 1, nil
+}
+
+func (JSONRPCCodec) ReadFrame(r *bufio.Reader) ([]byte, map[string]interface{}, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(byteReader{r}).Decode(&raw); err != nil {
+		return nil, nil, err
+	}
+	var call struct {
+		ID     interface{}     `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.Unmarshal(raw, &call); err != nil {
+		return nil, nil, err
+	}
+	meta := map[string]interface{}{
+		"id":     call.ID,
+		"method": call.Method,
+	}
+	if call.Params != nil {
+		return []byte(call.Params), meta, nil
+	}
+	return []byte("{}"), meta, nil
+}
+
+func (JSONRPCCodec) WriteResponse(w io.Writer, resp Response, meta map[string]interface{}) error {
+	envelope := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      meta["id"],
+	}
+	if resp.Status >= 200 && resp.Status < 300 {
+		var result interface{}
+		if err := json.Unmarshal(resp.Body, &result); err != nil {
+			result = string(resp.Body)
+		}
+		envelope["result"] = result
+	} else {
+		envelope["error"] = map[string]interface{}{
+			"code":    resp.Status,
+			"message": string(resp.Body),
+		}
+	}
+	return json.NewEncoder(w).Encode(envelope)
+}
+
+// codecFor resolves the -codec flag to a Codec implementation.
+func codecFor(name string) (Codec, error) {
+	switch name {
+	case "line", "":
+		return NewlineCodec{}, nil
+	case "length-prefixed":
+		return LengthPrefixCodec{}, nil
+	case "netstring":
+		return NetstringCodec{}, nil
+	case "http":
+		return HTTPCodec{}, nil
+	case "jsonrpc":
+		return JSONRPCCodec{}, nil
+	default:
+		return nil, fmt.Errorf("unknown codec %q", name)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestNewlineCodecReadsSuccessiveFrames is a regression test for a bug where
+// each ReadFrame call wrapped the connection in a brand-new bufio.Reader,
+// discarding any bytes it had speculatively buffered past the current
+// frame's boundary. Two frames arriving in a single Write must both be
+// readable off one shared *bufio.Reader.
+func TestNewlineCodecReadsSuccessiveFrames(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("line1\nline2\n"))
+	codec := NewlineCodec{}
+
+	first, _, err := codec.ReadFrame(r)
+	if err != nil {
+		t.Fatalf("first ReadFrame: %v", err)
+	}
+	if string(first) != "line1" {
+		t.Fatalf("first frame = %q, want %q", first, "line1")
+	}
+
+	second, _, err := codec.ReadFrame(r)
+	if err != nil {
+		t.Fatalf("second ReadFrame: %v", err)
+	}
+	if string(second) != "line2" {
+		t.Fatalf("second frame = %q, want %q", second, "line2")
+	}
+}
+
+func TestLengthPrefixCodecRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	codec := LengthPrefixCodec{}
+
+	if err := codec.WriteResponse(&buf, Response{Body: []byte("hello")}, nil); err != nil {
+		t.Fatalf("WriteResponse: %v", err)
+	}
+
+	frame, _, err := codec.ReadFrame(bufio.NewReader(&buf))
+	if err != nil {
+		t.Fatalf("ReadFrame: %v", err)
+	}
+	if string(frame) != "hello" {
+		t.Fatalf("frame = %q, want %q", frame, "hello")
+	}
+}
+
+func TestLengthPrefixCodecReadsSuccessiveFrames(t *testing.T) {
+	var buf bytes.Buffer
+	codec := LengthPrefixCodec{}
+	codec.WriteResponse(&buf, Response{Body: []byte("first")}, nil)
+	codec.WriteResponse(&buf, Response{Body: []byte("second")}, nil)
+
+	r := bufio.NewReader(&buf)
+	first, _, err := codec.ReadFrame(r)
+	if err != nil || string(first) != "first" {
+		t.Fatalf("first frame = %q, %v, want %q", first, err, "first")
+	}
+	second, _, err := codec.ReadFrame(r)
+	if err != nil || string(second) != "second" {
+		t.Fatalf("second frame = %q, %v, want %q", second, err, "second")
+	}
+}
+
+func TestNetstringCodecRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	codec := NetstringCodec{}
+
+	if err := codec.WriteResponse(&buf, Response{Body: []byte("hello")}, nil); err != nil {
+		t.Fatalf("WriteResponse: %v", err)
+	}
+	if buf.String() != "5:hello," {
+		t.Fatalf("wire format = %q, want %q", buf.String(), "5:hello,")
+	}
+
+	frame, _, err := codec.ReadFrame(bufio.NewReader(&buf))
+	if err != nil {
+		t.Fatalf("ReadFrame: %v", err)
+	}
+	if string(frame) != "hello" {
+		t.Fatalf("frame = %q, want %q", frame, "hello")
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+const (
+	defaultMaxAttempts        = 3
+	defaultRetryBaseDelay     = 200 * time.Millisecond
+	defaultBreakerMaxFailures = 5
+	defaultBreakerCooldown    = 30 * time.Second
+)
+
+// gatewayConfig is the top-level shape of -config: a list of independently
+// configured listeners, each bridging its own TCP port to one or more HTTP
+// backends.
+type gatewayConfig struct {
+	Listeners []listenerConfig `json:"listeners"`
+}
+
+type listenerConfig struct {
+	Port            string        `json:"port"`
+	Codec           string        `json:"codec"`
+	Close           bool          `json:"close"`
+	BearerToken     string        `json:"bearerToken"`
+	Upstreams       []string      `json:"upstreams"`
+	Routes          []routeConfig `json:"routes"`
+	MaxAttempts     int           `json:"maxAttempts"`
+	RetryBaseDelay  durationMs    `json:"retryBaseDelayMs"`
+	BreakerFailures int           `json:"breakerMaxFailures"`
+	BreakerCooldown durationMs    `json:"breakerCooldownMs"`
+
+	TLSCert     string `json:"tlsCert"`
+	TLSKey      string `json:"tlsKey"`
+	TLSClientCA string `json:"tlsClientCA"`
+}
+
+// routeConfig matches a request to a set of upstreams. match selects what to
+// compare value against: "method" (JSON-RPC method), "path" (HTTP path) or
+// "field" (a top-level field of the decoded JSON body). An empty match (or
+// "*") is a catch-all, typically the last route in the list.
+type routeConfig struct {
+	Match     string   `json:"match"`
+	Field     string   `json:"field,omitempty"`
+	Value     string   `json:"value,omitempty"`
+	Upstreams []string `json:"upstreams"`
+}
+
+// durationMs unmarshals a plain JSON number of milliseconds into a
+// time.Duration.
+type durationMs time.Duration
+
+func (d *durationMs) UnmarshalJSON(data []byte) error {
+	var ms int64
+	if err := json.Unmarshal(data, &ms); err != nil {
+		return err
+	}
+	*d = durationMs(time.Duration(ms) * time.Millisecond)
+	return nil
+}
+
+func loadGatewayConfig(path string) (*gatewayConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg gatewayConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (c listenerConfig) maxAttempts() int {
+	if c.MaxAttempts > 0 {
+		return c.MaxAttempts
+	}
+	return defaultMaxAttempts
+}
+
+func (c listenerConfig) retryBaseDelay() time.Duration {
+	if c.RetryBaseDelay > 0 {
+		return time.Duration(c.RetryBaseDelay)
+	}
+	return defaultRetryBaseDelay
+}
+
+func (c listenerConfig) breakerMaxFailures() int {
+	if c.BreakerFailures > 0 {
+		return c.BreakerFailures
+	}
+	return defaultBreakerMaxFailures
+}
+
+func (c listenerConfig) breakerCooldown() time.Duration {
+	if c.BreakerCooldown > 0 {
+		return time.Duration(c.BreakerCooldown)
+	}
+	return defaultBreakerCooldown
+}
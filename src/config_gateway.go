@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+var errNoRouteMatched = errors.New("no route matched this request")
+
+// route pairs a routeConfig with the balancer it dispatches to.
+type route struct {
+	config   routeConfig
+	balancer *balancer
+}
+
+// runConfigGateway starts one listener goroutine per entry in cfg, each with
+// its own codec, route table and retry/circuit-breaker settings, and blocks
+// forever. Every listener reuses the shared httpClient configured in main
+// from the top-level -upstream-* flags.
+func runConfigGateway(cfg *gatewayConfig) {
+	for _, lc := range cfg.Listeners {
+		go runConfigListener(lc)
+	}
+	select {}
+}
+
+func runConfigListener(lc listenerConfig) {
+	codec, err := codecFor(lc.Codec)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	routes := make([]route, 0, len(lc.Routes))
+	for _, rc := range lc.Routes {
+		routes = append(routes, route{
+			config:   rc,
+			balancer: newBalancer(rc.Upstreams, lc.breakerMaxFailures(), lc.breakerCooldown()),
+		})
+	}
+
+	var defaultBalancer *balancer
+	if len(lc.Upstreams) > 0 {
+		defaultBalancer = newBalancer(lc.Upstreams, lc.breakerMaxFailures(), lc.breakerCooldown())
+	}
+
+	tlsConfig, err := listenerTLSConfig(lc.TLSCert, lc.TLSKey, lc.TLSClientCA)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	dispatch := func(_ string, buffer string) (int, []byte, http.Header, error) {
+		b := selectBalancer(routes, defaultBalancer, buffer)
+		if b == nil {
+			log.Println(errNoRouteMatched)
+			return http.StatusBadGateway, []byte(errNoRouteMatched.Error()), nil, nil
+		}
+
+		status, body, headers, err := sendWithRetry(httpClient, b, lc.BearerToken, buffer, lc.maxAttempts(), lc.retryBaseDelay())
+		if err != nil {
+			log.Println(err)
+			return http.StatusBadGateway, []byte(err.Error()), nil, nil
+		}
+		return status, body, headers, nil
+	}
+
+	openSocket(lc.Port, lc.Close, "", codec, tlsConfig, dispatch)
+}
+
+func selectBalancer(routes []route, fallback *balancer, buffer string) *balancer {
+	var message map[string]interface{}
+	json.Unmarshal([]byte(buffer), &message)
+
+	for _, r := range routes {
+		if routeMatches(r.config, message) {
+			return r.balancer
+		}
+	}
+	return fallback
+}
+
+func routeMatches(rc routeConfig, message map[string]interface{}) bool {
+	switch rc.Match {
+	case "method":
+		method, _ := message["method"].(string)
+		return method == rc.Value
+	case "path":
+		path, _ := message["path"].(string)
+		return path == rc.Value
+	case "field":
+		var body map[string]interface{}
+		if bodyStr, ok := message["body"].(string); ok {
+			json.Unmarshal([]byte(bodyStr), &body)
+		}
+		value, ok := body[rc.Field]
+		if !ok {
+			return false
+		}
+		if rc.Value == "" {
+			return true
+		}
+		return fmt.Sprintf("%v", value) == rc.Value
+	case "", "*":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import "testing"
+
+func TestRouteMatchesMethod(t *testing.T) {
+	rc := routeConfig{Match: "method", Value: "Test.Say"}
+	if !routeMatches(rc, map[string]interface{}{"method": "Test.Say"}) {
+		t.Fatal("expected a matching method to match")
+	}
+	if routeMatches(rc, map[string]interface{}{"method": "Test.Other"}) {
+		t.Fatal("expected a different method not to match")
+	}
+}
+
+func TestRouteMatchesPath(t *testing.T) {
+	rc := routeConfig{Match: "path", Value: "/say"}
+	if !routeMatches(rc, map[string]interface{}{"path": "/say"}) {
+		t.Fatal("expected a matching path to match")
+	}
+}
+
+func TestRouteMatchesFieldAcrossJSONTypes(t *testing.T) {
+	rc := routeConfig{Match: "field", Field: "id", Value: "1"}
+	message := map[string]interface{}{"body": `{"id":1}`}
+	if !routeMatches(rc, message) {
+		t.Fatal("a numeric JSON field should match an equivalent string route value")
+	}
+
+	boolRC := routeConfig{Match: "field", Field: "admin", Value: "true"}
+	boolMessage := map[string]interface{}{"body": `{"admin":true}`}
+	if !routeMatches(boolRC, boolMessage) {
+		t.Fatal("a boolean JSON field should match an equivalent string route value")
+	}
+}
+
+func TestRouteMatchesFieldPresenceOnly(t *testing.T) {
+	rc := routeConfig{Match: "field", Field: "id"}
+	if !routeMatches(rc, map[string]interface{}{"body": `{"id":1}`}) {
+		t.Fatal("an empty route Value should match on field presence alone")
+	}
+	if routeMatches(rc, map[string]interface{}{"body": `{}`}) {
+		t.Fatal("a missing field should never match")
+	}
+}
+
+func TestRouteMatchesCatchAll(t *testing.T) {
+	if !routeMatches(routeConfig{Match: ""}, map[string]interface{}{}) {
+		t.Fatal("an empty match should catch all")
+	}
+	if !routeMatches(routeConfig{Match: "*"}, map[string]interface{}{}) {
+		t.Fatal("a \"*\" match should catch all")
+	}
+}
+
+func TestSelectBalancerFallsBackWhenNoRouteMatches(t *testing.T) {
+	fallback := newBalancer([]string{"http://fallback"}, 1, 0)
+	matched := newBalancer([]string{"http://matched"}, 1, 0)
+	routes := []route{{config: routeConfig{Match: "method", Value: "Test.Say"}, balancer: matched}}
+
+	b := selectBalancer(routes, fallback, `{"method":"Test.Other"}`)
+	if b != fallback {
+		t.Fatal("expected the fallback balancer when no route matches")
+	}
+}
+
+func TestSelectBalancerReturnsMatchedRoute(t *testing.T) {
+	fallback := newBalancer([]string{"http://fallback"}, 1, 0)
+	matched := newBalancer([]string{"http://matched"}, 1, 0)
+	routes := []route{{config: routeConfig{Match: "method", Value: "Test.Say"}, balancer: matched}}
+
+	b := selectBalancer(routes, fallback, `{"method":"Test.Say"}`)
+	if b != matched {
+		t.Fatal("expected the balancer of the matching route")
+	}
+}
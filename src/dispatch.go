@@ -0,0 +1,27 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+)
+
+// postJSON POSTs buffer as a JSON body to url with a bearer token, returning
+// the upstream status code, response body and headers.
+func postJSON(client *http.Client, url, bearer, buffer string) (int, []byte, http.Header, error) {
+	req, _ := http.NewRequest("POST", url, strings.NewReader(buffer))
+	req.Header.Add("Authorization", "Bearer "+bearer)
+	req.Header.Add("content-type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, resp.Header, err
+	}
+	return resp.StatusCode, body, resp.Header, nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// routeTable maps a JSON-RPC method name to the upstream URL it should be
+// forwarded to, e.g. "Test.Say" -> "http://backend/say".
+type routeTable map[string]string
+
+func (r routeTable) urlFor(method, fallback string) string {
+	if url, ok := r[method]; ok {
+		return url
+	}
+	return fallback
+}
+
+// routeFlags collects repeated -route Method=URL flags into a routeTable.
+type routeFlags struct {
+	routes routeTable
+}
+
+func (f *routeFlags) String() string {
+	return fmt.Sprintf("%v", f.routes)
+}
+
+func (f *routeFlags) Set(value string) error {
+	method, url, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("route %q must be in the form Method=URL", value)
+	}
+	if f.routes == nil {
+		f.routes = routeTable{}
+	}
+	f.routes[method] = url
+	return nil
+}
+
+type rpcCall struct {
+	Version string          `json:"jsonrpc"`
+	ID      interface{}     `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	Version string      `json:"jsonrpc"`
+	ID      interface{} `json:"id"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   interface{} `json:"error,omitempty"`
+}
+
+// handleJSONRPCConnection serves a single TCP connection in native JSON-RPC
+// 2.0 gateway mode: each request (or batch of requests) is routed by method
+// to an upstream URL, forwarded as an HTTP POST, and the HTTP response is
+// translated back into a JSON-RPC result or error.
+func handleJSONRPCConnection(c net.Conn, closeConnection bool, defaultURL string, routes routeTable, lspFraming bool, onMessage func(url, buffer string) (int, []byte, http.Header, error)) {
+	log.Printf("Accepted JSON-RPC connection from %s\n", c.RemoteAddr().String())
+	reader := bufio.NewReader(c)
+	for {
+		raw, err := readJSONRPCPayload(reader, lspFraming)
+		if err != nil {
+			log.Println(err)
+			c.Close()
+			return
+		}
+
+		if response := dispatchJSONRPC(raw, routes, defaultURL, onMessage); response != nil {
+			if err := writeJSONRPCPayload(c, response, lspFraming); err != nil {
+				log.Println(err)
+			}
+		}
+
+		if closeConnection {
+			c.Close()
+			return
+		}
+	}
+}
+
+// readJSONRPCPayload reads one JSON-RPC message: a bare JSON value, or, when
+// lsp is set, an LSP-style "Content-Length: N\r\n\r\n" header followed by N
+// bytes of JSON.
+func readJSONRPCPayload(r *bufio.Reader, lsp bool) (json.RawMessage, error) {
+	if !lsp {
+		var raw json.RawMessage
+		if err := json.NewDecoder(byteReader{r}).Decode(&raw); err != nil {
+			return nil, err
+		}
+		return raw, nil
+	}
+
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("jsonrpc: invalid Content-Length %q: %w", value, err)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("jsonrpc: missing Content-Length header")
+	}
+
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func writeJSONRPCPayload(w io.Writer, payload []byte, lsp bool) error {
+	if lsp {
+		if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(payload)); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// dispatchJSONRPC handles either a single request object or a batch (JSON
+// array) of requests, routing each by method and collecting the responses.
+// It returns nil when nothing should be written back, e.g. a lone
+// notification or a batch made up entirely of notifications.
+func dispatchJSONRPC(raw json.RawMessage, routes routeTable, defaultURL string, onMessage func(url, buffer string) (int, []byte, http.Header, error)) []byte {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var calls []json.RawMessage
+		if err := json.Unmarshal(trimmed, &calls); err != nil {
+			return marshalRPCError(nil, -32700, "Parse error")
+		}
+
+		var responses []json.RawMessage
+		for _, call := range calls {
+			if resp := dispatchSingleRPC(call, routes, defaultURL, onMessage); resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+		if len(responses) == 0 {
+			return nil
+		}
+		out, _ := json.Marshal(responses)
+		return out
+	}
+
+	return dispatchSingleRPC(trimmed, routes, defaultURL, onMessage)
+}
+
+func dispatchSingleRPC(raw json.RawMessage, routes routeTable, defaultURL string, onMessage func(url, buffer string) (int, []byte, http.Header, error)) []byte {
+	var call rpcCall
+	if err := json.Unmarshal(raw, &call); err != nil {
+		return marshalRPCError(nil, -32700, "Parse error")
+	}
+
+	status, body, _, err := onMessage(routes.urlFor(call.Method, defaultURL), string(raw))
+
+	// No id means this was a notification: the caller isn't waiting on a reply.
+	if call.ID == nil {
+		return nil
+	}
+	if err != nil {
+		return marshalRPCError(call.ID, -32000, err.Error())
+	}
+	if status < 200 || status >= 300 {
+		return marshalRPCError(call.ID, status, string(body))
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		result = string(body)
+	}
+	out, _ := json.Marshal(rpcResponse{Version: "2.0", ID: call.ID, Result: result})
+	return out
+}
+
+func marshalRPCError(id interface{}, code int, message string) []byte {
+	out, _ := json.Marshal(rpcResponse{
+		Version: "2.0",
+		ID:      id,
+		Error:   map[string]interface{}{"code": code, "message": message},
+	})
+	return out
+}
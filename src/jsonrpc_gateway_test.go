@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func stubOnMessage(status int, body []byte, err error) func(url, buffer string) (int, []byte, http.Header, error) {
+	return func(url, buffer string) (int, []byte, http.Header, error) {
+		return status, body, nil, err
+	}
+}
+
+func TestDispatchSingleRPCReturnsResult(t *testing.T) {
+	onMessage := stubOnMessage(http.StatusOK, []byte(`{"ok":true}`), nil)
+	raw := json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"Test.Say"}`)
+
+	resp := dispatchSingleRPC(raw, routeTable{}, "http://backend", onMessage)
+	if resp == nil {
+		t.Fatal("expected a response for a request with an id")
+	}
+
+	var parsed rpcResponse
+	if err := json.Unmarshal(resp, &parsed); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if parsed.ID != float64(1) {
+		t.Fatalf("ID = %v, want 1", parsed.ID)
+	}
+	if parsed.Error != nil {
+		t.Fatalf("Error = %v, want nil", parsed.Error)
+	}
+}
+
+func TestDispatchSingleRPCNotificationReturnsNoResponse(t *testing.T) {
+	onMessage := stubOnMessage(http.StatusOK, []byte(`{"ok":true}`), nil)
+	raw := json.RawMessage(`{"jsonrpc":"2.0","method":"Test.Notify"}`)
+
+	if resp := dispatchSingleRPC(raw, routeTable{}, "http://backend", onMessage); resp != nil {
+		t.Fatalf("notification should produce no response, got %s", resp)
+	}
+}
+
+func TestDispatchSingleRPCUpstreamErrorStatus(t *testing.T) {
+	onMessage := stubOnMessage(http.StatusInternalServerError, []byte("boom"), nil)
+	raw := json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"Test.Say"}`)
+
+	resp := dispatchSingleRPC(raw, routeTable{}, "http://backend", onMessage)
+	var parsed rpcResponse
+	if err := json.Unmarshal(resp, &parsed); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if parsed.Error == nil {
+		t.Fatal("expected an error envelope for a non-2xx upstream status")
+	}
+}
+
+func TestDispatchJSONRPCBatchSkipsNotifications(t *testing.T) {
+	onMessage := stubOnMessage(http.StatusOK, []byte(`{"ok":true}`), nil)
+	raw := json.RawMessage(`[
+		{"jsonrpc":"2.0","id":1,"method":"Test.A"},
+		{"jsonrpc":"2.0","method":"Test.Notify"},
+		{"jsonrpc":"2.0","id":2,"method":"Test.B"}
+	]`)
+
+	resp := dispatchJSONRPC(raw, routeTable{}, "http://backend", onMessage)
+	var batch []rpcResponse
+	if err := json.Unmarshal(resp, &batch); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if len(batch) != 2 {
+		t.Fatalf("batch responses = %d, want 2 (notification excluded)", len(batch))
+	}
+}
+
+func TestDispatchJSONRPCAllNotificationBatchReturnsNoResponse(t *testing.T) {
+	onMessage := stubOnMessage(http.StatusOK, []byte(`{"ok":true}`), nil)
+	raw := json.RawMessage(`[
+		{"jsonrpc":"2.0","method":"Test.Notify1"},
+		{"jsonrpc":"2.0","method":"Test.Notify2"}
+	]`)
+
+	if resp := dispatchJSONRPC(raw, routeTable{}, "http://backend", onMessage); resp != nil {
+		t.Fatalf("all-notification batch should produce no response, got %s", resp)
+	}
+}
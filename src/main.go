@@ -2,57 +2,168 @@ package main
 
 import (
 	"bufio"
+	"crypto/tls"
 	"encoding/json"
 	"flag"
 	"log"
 	"net"
 	"net/http"
 	"os"
-	"strings"
 )
 
-func main() {
-	port, closeConnection, url := parseFlags()
-	openSocket(*port, *closeConnection, *url, onMessage)
+// httpClient is the client used to call the upstream HTTP endpoint. It is
+// built once in main from the -upstream-* flags, since it may carry a
+// configured mTLS transport.
+var httpClient = &http.Client{}
+
+type flags struct {
+	port            string
+	closeConnection bool
+	url             string
+	codec           string
+	routes          routeTable
+	lspFraming      bool
+	config          string
+	proto           string
+
+	tlsCert     string
+	tlsKey      string
+	tlsClientCA string
+
+	upstreamCA   string
+	upstreamCert string
+	upstreamKey  string
 }
 
-func onMessage(url string, buffer string) {
-	bearer := os.Getenv("TOKEN")
-	client := &http.Client{}
-	req, _ := http.NewRequest("POST", url, strings.NewReader(buffer))
-	req.Header.Add("Authorization", "Bearer "+bearer)
-	req.Header.Add("content-type", "application/json")
-	resp, err := client.Do(req)
+func main() {
+	cfg := parseFlags()
+
+	client, err := upstreamHTTPClient(cfg.upstreamCA, cfg.upstreamCert, cfg.upstreamKey)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	httpClient = client
+
+	if cfg.config != "" {
+		if cfg.tlsCert != "" || cfg.tlsKey != "" || cfg.tlsClientCA != "" {
+			log.Fatalln("-tls-cert/-tls-key/-tls-client-ca have no effect with -config; set tlsCert/tlsKey/tlsClientCA per listener in the config file instead")
+		}
+
+		gatewayCfg, err := loadGatewayConfig(cfg.config)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		runConfigGateway(gatewayCfg)
+		return
+	}
+
+	if cfg.proto == "udp" {
+		openUDPSocket(cfg.port, cfg.url, onMessage)
+		return
+	}
+	if cfg.proto == "both" {
+		go openUDPSocket(cfg.port, cfg.url, onMessage)
+	}
+
+	tlsConfig, err := listenerTLSConfig(cfg.tlsCert, cfg.tlsKey, cfg.tlsClientCA)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if cfg.codec == "jsonrpc-gateway" {
+		openJSONRPCSocket(cfg.port, cfg.closeConnection, cfg.url, cfg.routes, cfg.lspFraming, tlsConfig, onMessage)
+		return
+	}
+
+	codec, err := codecFor(cfg.codec)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	openSocket(cfg.port, cfg.closeConnection, cfg.url, codec, tlsConfig, onMessage)
+}
 
+func onMessage(url string, buffer string) (int, []byte, http.Header, error) {
+	status, body, headers, err := postJSON(httpClient, url, os.Getenv("TOKEN"), buffer)
 	if err != nil {
 		log.Println(err)
+		return status, body, headers, err
+	}
+
+	if status == http.StatusOK {
+		var result map[string]interface{}
+		json.Unmarshal(body, &result)
+		log.Println(result["status"])
 	} else {
-		if resp.Status == "200" {
-			var result map[string]interface{}
-			json.NewDecoder(resp.Body).Decode(&result)
-			log.Println(result["status"])
-		} else {
-			log.Println("Response status: " + resp.Status)
-		}
-		defer resp.Body.Close()
+		log.Println("Response status: " + http.StatusText(status))
 	}
+
+	return status, body, headers, nil
 }
 
-func parseFlags() (*string, *bool, *string) {
+func parseFlags() flags {
 	port := flag.String("port", "7777", "port number")
 	closeConnection := flag.Bool("close", true, "Close connection")
 	url := flag.String("url", "http://localhost:5000/register", "Destination endpoint")
+	codec := flag.String("codec", "line", "Framing codec: line, length-prefixed, netstring, http, jsonrpc, jsonrpc-gateway")
+	routes := &routeFlags{}
+	flag.Var(routes, "route", "JSON-RPC method routing, Method=URL (repeatable, jsonrpc-gateway only)")
+	lspFraming := flag.Bool("lsp-framing", false, "Use LSP-style Content-Length framing (jsonrpc-gateway only)")
+	config := flag.String("config", "", "JSON config file declaring multiple listeners with routing, retries and circuit breaking (overrides -port/-url/-codec)")
+	proto := flag.String("proto", "tcp", "Protocol to listen on: tcp, udp, both")
+
+	tlsCert := flag.String("tls-cert", "", "TLS certificate for the TCP listener")
+	tlsKey := flag.String("tls-key", "", "TLS private key for the TCP listener")
+	tlsClientCA := flag.String("tls-client-ca", "", "CA to verify TCP client certificates against (enables mTLS)")
+
+	upstreamCA := flag.String("upstream-ca", "", "CA to verify the upstream HTTPS endpoint against")
+	upstreamCert := flag.String("upstream-cert", "", "Client certificate for mTLS against the upstream endpoint")
+	upstreamKey := flag.String("upstream-key", "", "Client private key for mTLS against the upstream endpoint")
+
 	flag.Parse()
-	return port, closeConnection, url
+
+	return flags{
+		port:            *port,
+		closeConnection: *closeConnection,
+		url:             *url,
+		codec:           *codec,
+		routes:          routes.routes,
+		lspFraming:      *lspFraming,
+		config:          *config,
+		proto:           *proto,
+		tlsCert:         *tlsCert,
+		tlsKey:          *tlsKey,
+		tlsClientCA:     *tlsClientCA,
+		upstreamCA:      *upstreamCA,
+		upstreamCert:    *upstreamCert,
+		upstreamKey:     *upstreamKey,
+	}
 }
 
-func openSocket(port string, closeConnection bool, url string, onMessage func(url string, buffer string)) {
+func openSocket(port string, closeConnection bool, url string, codec Codec, tlsConfig *tls.Config, onMessage func(url string, buffer string) (int, []byte, http.Header, error)) {
 	PORT := "localhost:" + port
-	l, err := net.Listen("tcp4", PORT)
+	l, err := listen(PORT, tlsConfig)
+	if err != nil {
+		log.Fatalln(err)
+	}
 	log.Printf("Serving %s\n", l.Addr().String())
+	defer l.Close()
+
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		go handleConnection(c, closeConnection, url, codec, onMessage)
+	}
+}
+
+func openJSONRPCSocket(port string, closeConnection bool, url string, routes routeTable, lspFraming bool, tlsConfig *tls.Config, onMessage func(url string, buffer string) (int, []byte, http.Header, error)) {
+	PORT := "localhost:" + port
+	l, err := listen(PORT, tlsConfig)
 	if err != nil {
 		log.Fatalln(err)
 	}
+	log.Printf("Serving JSON-RPC gateway on %s\n", l.Addr().String())
 	defer l.Close()
 
 	for {
@@ -60,32 +171,47 @@ func openSocket(port string, closeConnection bool, url string, onMessage func(ur
 		if err != nil {
 			log.Fatalln(err)
 		}
-		go handleConnection(c, closeConnection, url, onMessage)
+		go handleJSONRPCConnection(c, closeConnection, url, routes, lspFraming, onMessage)
 	}
 }
 
-func handleConnection(c net.Conn, closeConnection bool, url string, onMessage func(url string, buffer string)) {
+func handleConnection(c net.Conn, closeConnection bool, url string, codec Codec, onMessage func(url string, buffer string) (int, []byte, http.Header, error)) {
 	log.Printf("Accepted connection from %s\n", c.RemoteAddr().String())
+	reader := bufio.NewReader(c)
 	for {
 		ip, port, err := net.SplitHostPort(c.RemoteAddr().String())
-		netData, err := bufio.NewReader(c).ReadString('\n')
+		frame, meta, err := codec.ReadFrame(reader)
 		if err != nil {
 			log.Println(err)
+			c.Close()
+			return
 		}
 
 		message := map[string]interface{}{
-			"body":   strings.TrimSpace(netData),
+			"body":   string(frame),
 			"ipFrom": ip,
 			"port":   port,
 		}
+		for key, value := range meta {
+			message[key] = value
+		}
+		if cn, ok := clientCommonName(c); ok {
+			message["clientCN"] = cn
+		}
 
 		log.Printf("Making request with %s\n", message)
 		bytesRepresentation, err := json.Marshal(message)
 		if err != nil {
 			log.Println(err)
 		} else {
-			//buffer := bytes.NewBuffer(bytesRepresentation)
-			onMessage(url, string(bytesRepresentation))
+			status, body, headers, err := onMessage(url, string(bytesRepresentation))
+			if err != nil {
+				log.Println(err)
+				status, body, headers = http.StatusBadGateway, []byte(err.Error()), nil
+			}
+			if writeErr := codec.WriteResponse(c, Response{Status: status, Body: body, Headers: headers}, meta); writeErr != nil {
+				log.Println(writeErr)
+			}
 		}
 
 		if closeConnection {
@@ -93,5 +219,4 @@ func handleConnection(c net.Conn, closeConnection bool, url string, onMessage fu
 			return
 		}
 	}
-	c.Close()
 }
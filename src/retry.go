@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// sendWithRetry picks a healthy upstream from b, POSTs the message, and
+// retries with exponential backoff (capped at maxAttempts) on error or a 5xx
+// response, recording each outcome against that upstream's circuit breaker.
+func sendWithRetry(client *http.Client, b *balancer, bearer, buffer string, maxAttempts int, baseDelay time.Duration) (int, []byte, http.Header, error) {
+	var lastErr error
+	delay := baseDelay
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		up, err := b.pick()
+		if err != nil {
+			return 0, nil, nil, err
+		}
+
+		status, body, headers, err := postJSON(client, up.url, bearer, buffer)
+		if err == nil && status < http.StatusInternalServerError {
+			up.breaker.recordSuccess()
+			return status, body, headers, nil
+		}
+
+		up.breaker.recordFailure()
+		if err == nil {
+			err = fmt.Errorf("upstream %s returned status %d", up.url, status)
+		}
+		lastErr = err
+
+		if attempt < maxAttempts-1 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return 0, nil, nil, lastErr
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSendWithRetrySucceedsWithoutRetrying(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	b := newBalancer([]string{server.URL}, 5, time.Minute)
+	status, body, _, err := sendWithRetry(&http.Client{}, b, "", "{}", 3, time.Millisecond)
+	if err != nil {
+		t.Fatalf("sendWithRetry: %v", err)
+	}
+	if status != http.StatusOK || string(body) != "ok" {
+		t.Fatalf("status/body = %d/%q, want 200/ok", status, body)
+	}
+	if hits != 1 {
+		t.Fatalf("hits = %d, want 1", hits)
+	}
+}
+
+func TestSendWithRetryStopsAtMaxAttempts(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	// breakerMaxFailures is higher than maxAttempts so the breaker never
+	// opens mid-retry; every attempt should reach the upstream.
+	b := newBalancer([]string{server.URL}, 10, time.Minute)
+	_, _, _, err := sendWithRetry(&http.Client{}, b, "", "{}", 3, time.Millisecond)
+	if err == nil {
+		t.Fatal("expected an error once every retry returns a 5xx")
+	}
+	if hits != 3 {
+		t.Fatalf("hits = %d, want 3 (maxAttempts)", hits)
+	}
+}
+
+func TestSendWithRetryStopsEarlyWhenBreakerOpens(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	// A single upstream whose breaker opens after the first failure: the
+	// next pick() should fail fast instead of exhausting maxAttempts.
+	b := newBalancer([]string{server.URL}, 1, time.Minute)
+	_, _, _, err := sendWithRetry(&http.Client{}, b, "", "{}", 5, time.Millisecond)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(err.Error(), "no healthy upstream") {
+		t.Fatalf("err = %v, want it to report no healthy upstream", err)
+	}
+	if hits != 1 {
+		t.Fatalf("hits = %d, want 1 (breaker should stop further attempts)", hits)
+	}
+}
+
+func TestSendWithRetryBacksOffBetweenAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	b := newBalancer([]string{server.URL}, 10, time.Minute)
+	baseDelay := 20 * time.Millisecond
+
+	start := time.Now()
+	sendWithRetry(&http.Client{}, b, "", "{}", 2, baseDelay)
+	elapsed := time.Since(start)
+
+	if elapsed < baseDelay {
+		t.Fatalf("elapsed = %v, want at least one backoff of %v between the 2 attempts", elapsed, baseDelay)
+	}
+}
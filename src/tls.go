@@ -0,0 +1,101 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+)
+
+// listenerTLSConfig builds the TLS server config for the TCP listener. It
+// returns nil when certFile/keyFile are both empty, meaning plain TCP.
+// clientCAFile, when set, turns on mutual TLS: client certificates are
+// required and verified against that CA.
+func listenerTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("tls: loading listener cert: %w", err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	if clientCAFile != "" {
+		pool, err := loadCertPool(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: loading client CA: %w", err)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
+
+// listen opens a TCP listener, upgrading to TLS when config is non-nil.
+func listen(addr string, config *tls.Config) (net.Listener, error) {
+	if config == nil {
+		return net.Listen("tcp4", addr)
+	}
+	return tls.Listen("tcp4", addr, config)
+}
+
+// clientCommonName returns the CommonName of the verified client certificate
+// on a mTLS connection, if any.
+func clientCommonName(c net.Conn) (string, bool) {
+	tlsConn, ok := c.(*tls.Conn)
+	if !ok {
+		return "", false
+	}
+	state := tlsConn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return "", false
+	}
+	return state.PeerCertificates[0].Subject.CommonName, true
+}
+
+// upstreamHTTPClient builds the http.Client used to call the upstream HTTP
+// endpoint when any of the upstream-* flags are set: caFile sets the roots the
+// upstream's certificate is verified against, and certFile/keyFile add a
+// client certificate for mutual TLS.
+func upstreamHTTPClient(caFile, certFile, keyFile string) (*http.Client, error) {
+	if caFile == "" && certFile == "" && keyFile == "" {
+		return &http.Client{}, nil
+	}
+
+	tlsConfig := &tls.Config{}
+
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: loading upstream CA: %w", err)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: loading upstream client cert: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}, nil
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	return pool, nil
+}
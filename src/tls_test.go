@@ -0,0 +1,129 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCert generates a self-signed ECDSA cert/key pair and writes them
+// as PEM files under dir, returning their paths.
+func writeTestCert(t *testing.T, dir, name string) (certPath, keyPath string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		IsCA:         true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+
+	certPath = filepath.Join(dir, name+"-cert.pem")
+	keyPath = filepath.Join(dir, name+"-key.pem")
+
+	certOut, err := os.Create(certPath)
+	if err != nil {
+		t.Fatalf("create cert file: %v", err)
+	}
+	defer certOut.Close()
+	if err := pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+		t.Fatalf("encode cert: %v", err)
+	}
+
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey: %v", err)
+	}
+	keyOut, err := os.Create(keyPath)
+	if err != nil {
+		t.Fatalf("create key file: %v", err)
+	}
+	defer keyOut.Close()
+	if err := pem.Encode(keyOut, &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}); err != nil {
+		t.Fatalf("encode key: %v", err)
+	}
+
+	return certPath, keyPath
+}
+
+func TestListenerTLSConfigPlainWhenUnset(t *testing.T) {
+	config, err := listenerTLSConfig("", "", "")
+	if err != nil {
+		t.Fatalf("listenerTLSConfig: %v", err)
+	}
+	if config != nil {
+		t.Fatal("expected a nil TLS config for plain TCP")
+	}
+}
+
+func TestListenerTLSConfigServerOnly(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeTestCert(t, dir, "server")
+
+	config, err := listenerTLSConfig(certPath, keyPath, "")
+	if err != nil {
+		t.Fatalf("listenerTLSConfig: %v", err)
+	}
+	if config == nil {
+		t.Fatal("expected a non-nil TLS config")
+	}
+	if len(config.Certificates) != 1 {
+		t.Fatalf("Certificates = %d, want 1", len(config.Certificates))
+	}
+	if config.ClientAuth != tls.NoClientCert {
+		t.Fatalf("ClientAuth = %v, want NoClientCert when no client CA is set", config.ClientAuth)
+	}
+}
+
+func TestListenerTLSConfigMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeTestCert(t, dir, "server")
+	caPath, _ := writeTestCert(t, dir, "client-ca")
+
+	config, err := listenerTLSConfig(certPath, keyPath, caPath)
+	if err != nil {
+		t.Fatalf("listenerTLSConfig: %v", err)
+	}
+	if config.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Fatalf("ClientAuth = %v, want RequireAndVerifyClientCert when a client CA is set", config.ClientAuth)
+	}
+	if config.ClientCAs == nil {
+		t.Fatal("expected ClientCAs to be populated")
+	}
+}
+
+func TestListenerTLSConfigRejectsMissingCert(t *testing.T) {
+	if _, err := listenerTLSConfig("/no/such/cert.pem", "/no/such/key.pem", ""); err == nil {
+		t.Fatal("expected an error when the cert/key files don't exist")
+	}
+}
+
+func TestClientCommonNameFalseForPlainConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	if _, ok := clientCommonName(server); ok {
+		t.Fatal("expected no common name for a non-TLS connection")
+	}
+}
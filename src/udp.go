@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// openUDPSocket listens for UDP datagrams and feeds them into the same
+// "read frame -> wrap as JSON -> POST" pipeline as the TCP listener. Each
+// datagram is treated as one complete frame.
+func openUDPSocket(port string, url string, onMessage func(url string, buffer string) (int, []byte, http.Header, error)) {
+	addr, err := net.ResolveUDPAddr("udp4", "localhost:"+port)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	conn, err := net.ListenUDP("udp4", addr)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	log.Printf("Serving UDP %s\n", conn.LocalAddr().String())
+	defer conn.Close()
+
+	buf := make([]byte, 65535)
+	for {
+		n, remoteAddr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		datagram := make([]byte, n)
+		copy(datagram, buf[:n])
+		go handleDatagram(datagram, remoteAddr, url, onMessage)
+	}
+}
+
+func handleDatagram(datagram []byte, remoteAddr *net.UDPAddr, url string, onMessage func(url string, buffer string) (int, []byte, http.Header, error)) {
+	message := map[string]interface{}{
+		"body":   strings.TrimSpace(string(datagram)),
+		"ipFrom": remoteAddr.IP.String(),
+		"port":   strconv.Itoa(remoteAddr.Port),
+	}
+
+	log.Printf("Making request with %s\n", message)
+	bytesRepresentation, err := json.Marshal(message)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	if _, _, _, err := onMessage(url, string(bytesRepresentation)); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestHandleDatagramBuildsJSONEnvelope(t *testing.T) {
+	done := make(chan string, 1)
+	onMessage := func(url, buffer string) (int, []byte, http.Header, error) {
+		done <- buffer
+		return http.StatusOK, nil, nil, nil
+	}
+
+	remoteAddr := &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 5555}
+	handleDatagram([]byte(" hello \n"), remoteAddr, "http://backend", onMessage)
+
+	var message map[string]interface{}
+	if err := json.Unmarshal([]byte(<-done), &message); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if message["body"] != "hello" {
+		t.Fatalf("body = %v, want %q", message["body"], "hello")
+	}
+	if message["ipFrom"] != "127.0.0.1" {
+		t.Fatalf("ipFrom = %v, want %q", message["ipFrom"], "127.0.0.1")
+	}
+	if message["port"] != "5555" {
+		t.Fatalf("port = %v, want %q", message["port"], "5555")
+	}
+}
+
+func TestHandleDatagramLogsOnMessageErrorWithoutPanicking(t *testing.T) {
+	onMessage := func(url, buffer string) (int, []byte, http.Header, error) {
+		return 0, nil, nil, net.ErrClosed
+	}
+	remoteAddr := &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 5555}
+
+	handleDatagram([]byte("hello"), remoteAddr, "http://backend", onMessage)
+}